@@ -0,0 +1,78 @@
+package kvs
+
+import "bytes"
+
+// Backend is the storage interface that every KVS storage engine must
+// implement. It deals purely in raw bytes: encoding of application-level
+// terms into keys and values happens above this layer (see RocksDB in
+// kvs_stream.go), so that the same Put/Get/Delete/iteration semantics can
+// be reused across in-process engines (MemBackend), embedded engines
+// (BoltBackend, RocksDB) and remote engines alike. dbtest holds the
+// conformance suite every implementation must pass.
+//
+// Backend deliberately stops at that minimal contract. The higher-level
+// convenience methods on *RocksDB — Cut, Take, Next, Prev, Dir and the
+// Range API they're built on (kvs_range.go) — are RocksDB-specific: they
+// drive grocksdb iterators directly, Dir/Next/Prev skip the reserved
+// index/ and seq/ namespaces, and the Txn API needs a grocksdb.Snapshot,
+// which only RocksDB exposes. MemBackend, BoltBackend and RemoteBackend get the same
+// Put/Get/Delete/iterate guarantees via dbtest, but do not (yet) get
+// Cut/Take/Next/Prev/Dir for free; a caller that needs those on another
+// backend reimplements them against Backend's primitives, the way
+// RocksDB's Range does today.
+type Backend interface {
+	Put(key, value []byte) error
+	Get(key []byte) ([]byte, error)
+	Delete(key []byte) error
+	NewIterator(prefix, start []byte) (Iterator, error)
+	NewBatch() Batch
+	Snapshot() (Snapshot, error)
+	Close() error
+}
+
+// Iterator walks a range of key/value pairs in ascending key order.
+// Callers must call Release when done; Err reports any error that
+// terminated iteration early.
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Err() error
+	Release()
+}
+
+// Batch buffers Put/Delete operations so they can be applied to a Backend
+// atomically via Write.
+type Batch interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	Write() error
+	Reset()
+}
+
+// Snapshot is a read-only, point-in-time view of a Backend. Reads against
+// a Snapshot are unaffected by writes that happen after it was taken.
+type Snapshot interface {
+	Get(key []byte) ([]byte, error)
+	NewIterator(prefix, start []byte) (Iterator, error)
+	Release()
+}
+
+// prefixUpperBound returns the smallest key that is strictly greater than
+// every key with the given prefix, i.e. the exclusive upper bound of the
+// prefix range [prefix, upperBound). It returns nil if prefix consists
+// entirely of 0xFF bytes (or is empty), meaning the range has no upper
+// bound and runs to the end of the keyspace.
+//
+// This replaces the naive append(prefix, 0xFF), which is wrong whenever
+// prefix itself already ends in one or more 0xFF bytes: appending another
+// 0xFF does not produce a key greater than e.g. prefix+[0xFF, 0x00].
+func prefixUpperBound(prefix []byte) []byte {
+	bound := bytes.TrimRight(prefix, string([]byte{0xFF}))
+	if len(bound) == 0 {
+		return nil
+	}
+	bound = append([]byte{}, bound...)
+	bound[len(bound)-1]++
+	return bound
+}
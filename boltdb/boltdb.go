@@ -0,0 +1,213 @@
+// Package boltdb provides a kvs.Backend backed by BoltDB (bbolt), an
+// embedded, single-file B+tree store. Unlike memdb, it persists to disk
+// and provides real MVCC snapshots without CGO.
+package boltdb
+
+import (
+	"bytes"
+
+	"github.com/iho/kvs"
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucketName is the single bucket every key/value pair is stored under.
+var bucketName = []byte("kvs")
+
+// BoltBackend is a kvs.Backend backed by a BoltDB file.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) the BoltDB file at path and returns
+// a Backend backed by it.
+func Open(path string) (*BoltBackend, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltBackend{db: db}, nil
+}
+
+// Put implements kvs.Backend.
+func (b *BoltBackend) Put(key, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(key, value)
+	})
+}
+
+// Get implements kvs.Backend.
+func (b *BoltBackend) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucketName).Get(key); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Delete implements kvs.Backend.
+func (b *BoltBackend) Delete(key []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(key)
+	})
+}
+
+// NewIterator implements kvs.Backend.
+func (b *BoltBackend) NewIterator(prefix, start []byte) (kvs.Iterator, error) {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return newBoltIterator(tx, prefix, start), nil
+}
+
+// NewBatch implements kvs.Backend.
+func (b *BoltBackend) NewBatch() kvs.Batch {
+	return &boltBatch{db: b.db}
+}
+
+// Snapshot implements kvs.Backend.
+func (b *BoltBackend) Snapshot() (kvs.Snapshot, error) {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &boltSnapshot{tx: tx}, nil
+}
+
+// Close implements kvs.Backend.
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+// boltIterator adapts a bolt.Cursor, scoped to a read-only transaction it
+// owns, to the kvs.Iterator interface.
+type boltIterator struct {
+	tx    *bolt.Tx
+	c     *bolt.Cursor
+	upper []byte
+
+	key, value []byte
+	started    bool
+}
+
+func newBoltIterator(tx *bolt.Tx, prefix, start []byte) *boltIterator {
+	seekKey := prefix
+	if start != nil {
+		seekKey = start
+	}
+	it := &boltIterator{tx: tx, c: tx.Bucket(bucketName).Cursor(), upper: upperBound(prefix)}
+	it.key, it.value = it.c.Seek(seekKey)
+	it.started = false
+	return it
+}
+
+func (it *boltIterator) Next() bool {
+	if !it.started {
+		it.started = true
+	} else {
+		it.key, it.value = it.c.Next()
+	}
+	if it.key == nil {
+		return false
+	}
+	if it.upper != nil && bytes.Compare(it.key, it.upper) >= 0 {
+		return false
+	}
+	return true
+}
+
+func (it *boltIterator) Key() []byte   { return it.key }
+func (it *boltIterator) Value() []byte { return it.value }
+func (it *boltIterator) Err() error    { return nil }
+func (it *boltIterator) Release() {
+	if it.tx != nil {
+		it.tx.Rollback()
+	}
+}
+
+// boltBatch buffers operations and applies them in a single bolt
+// transaction on Write, so they either all succeed or all fail.
+type boltBatch struct {
+	db  *bolt.DB
+	ops []func(*bolt.Bucket) error
+}
+
+func (b *boltBatch) Put(key, value []byte) error {
+	key, value = append([]byte{}, key...), append([]byte{}, value...)
+	b.ops = append(b.ops, func(bkt *bolt.Bucket) error { return bkt.Put(key, value) })
+	return nil
+}
+
+func (b *boltBatch) Delete(key []byte) error {
+	key = append([]byte{}, key...)
+	b.ops = append(b.ops, func(bkt *bolt.Bucket) error { return bkt.Delete(key) })
+	return nil
+}
+
+func (b *boltBatch) Write() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(bucketName)
+		for _, op := range b.ops {
+			if err := op(bkt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// boltSnapshot is backed by a long-lived read-only bolt transaction,
+// which bolt guarantees sees a consistent point-in-time view.
+type boltSnapshot struct {
+	tx *bolt.Tx
+}
+
+func (s *boltSnapshot) Get(key []byte) ([]byte, error) {
+	if v := s.tx.Bucket(bucketName).Get(key); v != nil {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+func (s *boltSnapshot) NewIterator(prefix, start []byte) (kvs.Iterator, error) {
+	seekKey := prefix
+	if start != nil {
+		seekKey = start
+	}
+	it := &boltIterator{tx: nil, c: s.tx.Bucket(bucketName).Cursor(), upper: upperBound(prefix)}
+	it.key, it.value = it.c.Seek(seekKey)
+	it.started = false
+	return it, nil
+}
+
+func (s *boltSnapshot) Release() {
+	s.tx.Rollback()
+}
+
+// upperBound returns the smallest key strictly greater than every key
+// with the given prefix, or nil if prefix has no upper bound.
+func upperBound(prefix []byte) []byte {
+	bound := bytes.TrimRight(prefix, string([]byte{0xFF}))
+	if len(bound) == 0 {
+		return nil
+	}
+	bound = append([]byte{}, bound...)
+	bound[len(bound)-1]++
+	return bound
+}
+
+var _ kvs.Backend = (*BoltBackend)(nil)
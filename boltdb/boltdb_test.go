@@ -0,0 +1,20 @@
+package boltdb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/iho/kvs"
+	"github.com/iho/kvs/boltdb"
+	"github.com/iho/kvs/dbtest"
+)
+
+func TestBoltBackend(t *testing.T) {
+	dbtest.TestBackend(t, func(t *testing.T) kvs.Backend {
+		b, err := boltdb.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		return b
+	})
+}
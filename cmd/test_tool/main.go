@@ -36,6 +36,7 @@ func main() {
 		db,
 		ro,
 		wo,
+		dbPath,
 	)
 
 	// Test Append operation
@@ -0,0 +1,230 @@
+// Package dbtest provides a shared conformance test suite for kvs.Backend
+// implementations, mirroring the pattern used by go-ethereum's ethdb/dbtest:
+// every backend (RocksDB, memdb, boltdb, ...) runs the exact same suite so
+// the higher-level KVS/RocksDB logic built on top of Backend can trust
+// that all of them behave identically.
+package dbtest
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/iho/kvs"
+)
+
+// TestBackend runs the full conformance suite against a fresh Backend
+// produced by newBackend. newBackend is called once per subtest so tests
+// do not interfere with each other.
+func TestBackend(t *testing.T, newBackend func(t *testing.T) kvs.Backend) {
+	t.Run("EmptyDB", func(t *testing.T) { testEmptyDB(t, newBackend(t)) })
+	t.Run("PutGetDelete", func(t *testing.T) { testPutGetDelete(t, newBackend(t)) })
+	t.Run("PrefixIteration", func(t *testing.T) { testPrefixIteration(t, newBackend(t)) })
+	t.Run("Seek", func(t *testing.T) { testSeek(t, newBackend(t)) })
+	t.Run("SnapshotIsolation", func(t *testing.T) { testSnapshotIsolation(t, newBackend(t)) })
+	t.Run("BatchAtomicity", func(t *testing.T) { testBatchAtomicity(t, newBackend(t)) })
+}
+
+func testEmptyDB(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	v, err := b.Get([]byte("missing"))
+	if err != nil {
+		t.Fatalf("Get on empty db: %v", err)
+	}
+	if v != nil {
+		t.Fatalf("Get on empty db: got %q, want nil", v)
+	}
+
+	iter, err := b.NewIterator(nil, nil)
+	if err != nil {
+		t.Fatalf("NewIterator on empty db: %v", err)
+	}
+	defer iter.Release()
+	if iter.Next() {
+		t.Fatalf("Next on empty db iterator: got true, want false")
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("Err on empty db iterator: %v", err)
+	}
+
+	if err := b.Delete([]byte("missing")); err != nil {
+		t.Fatalf("Delete of missing key on empty db: %v", err)
+	}
+}
+
+func testPutGetDelete(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	key, value := []byte("k1"), []byte("v1")
+	if err := b.Put(key, value); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := b.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("Get: got %q, want %q", got, value)
+	}
+
+	if err := b.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	got, err = b.Get(key)
+	if err != nil {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("Get after Delete: got %q, want nil", got)
+	}
+}
+
+func testPrefixIteration(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	put := map[string]string{
+		"a/1": "1", "a/2": "2", "a/3": "3",
+		"b/1": "1",
+	}
+	for k, v := range put {
+		if err := b.Put([]byte(k), []byte(v)); err != nil {
+			t.Fatalf("Put(%q): %v", k, err)
+		}
+	}
+
+	iter, err := b.NewIterator([]byte("a/"), nil)
+	if err != nil {
+		t.Fatalf("NewIterator: %v", err)
+	}
+	defer iter.Release()
+
+	var got []string
+	for iter.Next() {
+		got = append(got, string(iter.Key()))
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	want := []string{"a/1", "a/2", "a/3"}
+	sort.Strings(got)
+	if !equalStrings(got, want) {
+		t.Fatalf("prefix iteration: got %v, want %v", got, want)
+	}
+}
+
+func testSeek(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	for _, k := range []string{"a/1", "a/2", "a/3"} {
+		if err := b.Put([]byte(k), []byte(k)); err != nil {
+			t.Fatalf("Put(%q): %v", k, err)
+		}
+	}
+
+	iter, err := b.NewIterator([]byte("a/"), []byte("a/2"))
+	if err != nil {
+		t.Fatalf("NewIterator: %v", err)
+	}
+	defer iter.Release()
+
+	var got []string
+	for iter.Next() {
+		got = append(got, string(iter.Key()))
+	}
+	want := []string{"a/2", "a/3"}
+	if !equalStrings(got, want) {
+		t.Fatalf("seek from a/2: got %v, want %v", got, want)
+	}
+}
+
+func testSnapshotIsolation(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	if err := b.Put([]byte("k"), []byte("before")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	snap, err := b.Snapshot()
+	if err != nil {
+		t.Skipf("Snapshot not supported: %v", err)
+	}
+	defer snap.Release()
+
+	if err := b.Put([]byte("k"), []byte("after")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := b.Put([]byte("k2"), []byte("after")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, err := snap.Get([]byte("k"))
+	if err != nil {
+		t.Fatalf("snapshot Get: %v", err)
+	}
+	if !bytes.Equal(got, []byte("before")) {
+		t.Fatalf("snapshot Get(k): got %q, want %q (writes after Snapshot leaked in)", got, "before")
+	}
+
+	got, err = snap.Get([]byte("k2"))
+	if err != nil {
+		t.Fatalf("snapshot Get(k2): %v", err)
+	}
+	if got != nil {
+		t.Fatalf("snapshot Get(k2): got %q, want nil (key written after Snapshot)", got)
+	}
+}
+
+func testBatchAtomicity(t *testing.T, b kvs.Backend) {
+	defer b.Close()
+
+	if err := b.Put([]byte("existing"), []byte("v")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	batch := b.NewBatch()
+	if err := batch.Put([]byte("new1"), []byte("v1")); err != nil {
+		t.Fatalf("batch Put: %v", err)
+	}
+	if err := batch.Put([]byte("new2"), []byte("v2")); err != nil {
+		t.Fatalf("batch Put: %v", err)
+	}
+	if err := batch.Delete([]byte("existing")); err != nil {
+		t.Fatalf("batch Delete: %v", err)
+	}
+
+	if got, _ := b.Get([]byte("new1")); got != nil {
+		t.Fatalf("Get(new1) before Write: got %q, want nil", got)
+	}
+
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch Write: %v", err)
+	}
+
+	for _, k := range []string{"new1", "new2"} {
+		if got, err := b.Get([]byte(k)); err != nil || got == nil {
+			t.Fatalf("Get(%q) after Write: got %q, err %v", k, got, err)
+		}
+	}
+	if got, _ := b.Get([]byte("existing")); got != nil {
+		t.Fatalf("Get(existing) after Write: got %q, want nil", got)
+	}
+
+	batch.Reset()
+	if err := batch.Write(); err != nil {
+		t.Fatalf("Write after Reset: %v", err)
+	}
+}
+
+func equalStrings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
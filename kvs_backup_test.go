@@ -0,0 +1,140 @@
+package kvs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iho/etf"
+	"github.com/iho/kvs"
+	"github.com/linxGnu/grocksdb"
+)
+
+func openTestRocksDB(t *testing.T) *kvs.RocksDB {
+	t.Helper()
+
+	opts := grocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+
+	dir := t.TempDir()
+	db, err := grocksdb.OpenDb(opts, dir)
+	if err != nil {
+		t.Fatalf("OpenDb: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	return kvs.NewRocksDB(db, grocksdb.NewDefaultReadOptions(), grocksdb.NewDefaultWriteOptions(), dir)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := openTestRocksDB(t)
+	for i := 0; i < 5; i++ {
+		if err := src.SaveReader(etf.Integer(i), etf.Atom("v")); err != nil {
+			t.Fatalf("SaveReader: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, kvs.Checkpoint{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := openTestRocksDB(t)
+	if err := dst.Import(&buf); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	srcCount, err := src.Count()
+	if err != nil {
+		t.Fatalf("src.Count: %v", err)
+	}
+	dstCount, err := dst.Count()
+	if err != nil {
+		t.Fatalf("dst.Count: %v", err)
+	}
+	if srcCount != dstCount {
+		t.Fatalf("record count after Import: got %d, want %d", dstCount, srcCount)
+	}
+
+	for i := 0; i < 5; i++ {
+		v, err := dst.LoadReader(etf.Integer(i))
+		if err != nil {
+			t.Fatalf("LoadReader(%d): %v", i, err)
+		}
+		if v != etf.Atom("v") {
+			t.Fatalf("LoadReader(%d): got %v, want %q", i, v, "v")
+		}
+	}
+}
+
+func TestExportSkipsReservedNamespaces(t *testing.T) {
+	src := openTestRocksDB(t)
+	table := kvs.NewKVSRocksDB(src)
+	table.RegisterIndex(etf.Atom("name"), func(data etf.ErlTerm) etf.ErlTerm {
+		return data.(etf.Tuple)[0]
+	})
+
+	for i := 0; i < 3; i++ {
+		id, err := table.Seq()
+		if err != nil {
+			t.Fatalf("Seq: %v", err)
+		}
+		if err := table.Put(id, etf.Tuple{etf.Atom("alice")}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, kvs.Checkpoint{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := openTestRocksDB(t)
+	if err := dst.Import(&buf); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	srcCount, err := src.Count()
+	if err != nil {
+		t.Fatalf("src.Count: %v", err)
+	}
+	dstCount, err := dst.Count()
+	if err != nil {
+		t.Fatalf("dst.Count: %v", err)
+	}
+	if srcCount != 3 || dstCount != srcCount {
+		t.Fatalf("record count after Import: got %d, want %d", dstCount, srcCount)
+	}
+
+	for i := 1; i <= 3; i++ {
+		v, err := dst.LoadReader(etf.Integer(i))
+		if err != nil {
+			t.Fatalf("LoadReader(%d): %v", i, err)
+		}
+		if _, ok := v.(etf.Tuple); !ok {
+			t.Fatalf("LoadReader(%d): got %v, want a Tuple", i, v)
+		}
+	}
+}
+
+func TestExportSkipsUnchangedCheckpoint(t *testing.T) {
+	src := openTestRocksDB(t)
+	if err := src.SaveReader(etf.Integer(1), etf.Atom("v")); err != nil {
+		t.Fatalf("SaveReader: %v", err)
+	}
+
+	cp := src.Checkpoint()
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, cp); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var full bytes.Buffer
+	if err := src.Export(&full, kvs.Checkpoint{}); err != nil {
+		t.Fatalf("Export (full): %v", err)
+	}
+
+	if buf.Len() >= full.Len() {
+		t.Fatalf("Export with an up-to-date Checkpoint: got %d bytes, want fewer than a full export's %d", buf.Len(), full.Len())
+	}
+}
@@ -0,0 +1,81 @@
+package kvs
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/iho/etf"
+)
+
+// indexKeyPrefix namespaces every secondary-index entry so it can never
+// collide with a primary record key.
+var indexKeyPrefix = []byte("index/")
+
+// indexExtractor describes one registered secondary index: field is the
+// ErlTerm callers pass to Index to query it, and extract pulls the
+// indexed value out of a record.
+type indexExtractor struct {
+	field   etf.ErlTerm
+	extract func(etf.ErlTerm) etf.ErlTerm
+}
+
+// encodeIndexPrefix returns the key prefix covering every index entry for
+// (field, value). If value is nil, it covers every entry for field.
+func encodeIndexPrefix(field, value etf.ErlTerm) ([]byte, error) {
+	fieldBytes, err := etf.EncodeErlTerm(field, true)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := append([]byte{}, indexKeyPrefix...)
+	buf = appendLenPrefixed(buf, fieldBytes)
+
+	if value == nil {
+		return buf, nil
+	}
+
+	valueBytes, err := etf.EncodeErlTerm(value, true)
+	if err != nil {
+		return nil, err
+	}
+	return appendLenPrefixed(buf, valueBytes), nil
+}
+
+// encodeIndexKey returns the full index entry key for a single (field,
+// value, primary key) triple.
+func encodeIndexKey(field, value, id etf.ErlTerm) ([]byte, error) {
+	prefix, err := encodeIndexPrefix(field, value)
+	if err != nil {
+		return nil, err
+	}
+	idBytes, err := etf.EncodeErlTerm(id, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(prefix, idBytes...), nil
+}
+
+// appendLenPrefixed appends data to buf preceded by its length, so that
+// two concatenated components can never be mistaken for a different split
+// of the same bytes.
+func appendLenPrefixed(buf, data []byte) []byte {
+	var lenBytes [4]byte
+	binary.BigEndian.PutUint32(lenBytes[:], uint32(len(data)))
+	buf = append(buf, lenBytes[:]...)
+	return append(buf, data...)
+}
+
+// indexValuesEqual reports whether two ErlTerm values encode to the same
+// bytes, which is the only notion of equality available for an opaque
+// etf.ErlTerm.
+func indexValuesEqual(a, b etf.ErlTerm) (bool, error) {
+	ab, err := etf.EncodeErlTerm(a, true)
+	if err != nil {
+		return false, err
+	}
+	bb, err := etf.EncodeErlTerm(b, true)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ab, bb), nil
+}
@@ -0,0 +1,205 @@
+package kvs
+
+import (
+	"bytes"
+
+	"github.com/iho/etf"
+	"github.com/linxGnu/grocksdb"
+)
+
+// RangeOptions configures a Range scan over a RocksDB instance.
+//
+// The scanned range is bounded below by Start (or by Prefix, if Start is
+// nil) and above by End (or by the next lexicographic key after Prefix,
+// if End is nil). Reverse walks the range from End towards Start instead
+// of the other way around. Limit, if positive, stops the scan after that
+// many keys. Snapshot, if set, scans a consistent point-in-time view
+// instead of the live database.
+type RangeOptions struct {
+	Prefix   etf.ErlTerm
+	Start    etf.ErlTerm
+	End      etf.ErlTerm
+	Reverse  bool
+	Limit    int
+	Snapshot *grocksdb.Snapshot
+}
+
+// RangeIterator walks the key/value pairs selected by a Range call, in
+// ascending key order (or descending, if RangeOptions.Reverse was set).
+// Callers must call Release when done.
+type RangeIterator struct {
+	iter    *grocksdb.Iterator
+	ro      *grocksdb.ReadOptions
+	ownsRO  bool
+	lower   []byte
+	upper   []byte
+	reverse bool
+	limit   int
+
+	count   int
+	started bool
+	err     error
+}
+
+// Range scans the range described by opts. It centralizes the
+// prefix/start/end bookkeeping that Cut, Take, Drop, Next, Prev and Dir
+// are built on, so next-lexicographic-key computation lives in exactly
+// one place (see prefixUpperBound) instead of being reimplemented, subtly
+// incorrectly, at every call site.
+func (r *RocksDB) Range(opts RangeOptions) (*RangeIterator, error) {
+	var prefix, start, end []byte
+	var err error
+
+	if opts.Prefix != nil {
+		if prefix, err = etf.EncodeErlTerm(opts.Prefix, true); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Start != nil {
+		if start, err = etf.EncodeErlTerm(opts.Start, true); err != nil {
+			return nil, err
+		}
+	}
+	if opts.End != nil {
+		if end, err = etf.EncodeErlTerm(opts.End, true); err != nil {
+			return nil, err
+		}
+	} else {
+		end = prefixUpperBound(prefix)
+	}
+
+	ro := r.ro
+	ownsRO := false
+	if opts.Snapshot != nil {
+		ro = grocksdb.NewDefaultReadOptions()
+		ro.SetSnapshot(opts.Snapshot)
+		ownsRO = true
+	}
+
+	iter := r.db.NewIterator(ro)
+	ri := &RangeIterator{
+		iter:    iter,
+		ro:      ro,
+		ownsRO:  ownsRO,
+		lower:   prefix,
+		upper:   end,
+		reverse: opts.Reverse,
+		limit:   opts.Limit,
+	}
+
+	lowerBound := prefix
+	if start != nil {
+		lowerBound = start
+	}
+	ri.lower = lowerBound
+
+	if opts.Reverse {
+		if end != nil {
+			iter.SeekForPrev(end)
+			for iter.Valid() {
+				key := iter.Key()
+				tooHigh := bytes.Compare(key.Data(), end) >= 0
+				key.Free()
+				if !tooHigh {
+					break
+				}
+				iter.Prev()
+			}
+		} else {
+			iter.SeekToLast()
+		}
+	} else if lowerBound != nil {
+		iter.Seek(lowerBound)
+	} else {
+		iter.SeekToFirst()
+	}
+
+	return ri, nil
+}
+
+// Next advances the iterator and reports whether a key/value pair is
+// available.
+func (ri *RangeIterator) Next() bool {
+	if ri.limit > 0 && ri.count >= ri.limit {
+		return false
+	}
+	if ri.started {
+		if ri.reverse {
+			ri.iter.Prev()
+		} else {
+			ri.iter.Next()
+		}
+	}
+	ri.started = true
+
+	if !ri.iter.Valid() {
+		return false
+	}
+
+	key := ri.iter.Key()
+	inRange := true
+	if ri.reverse {
+		if ri.lower != nil && bytes.Compare(key.Data(), ri.lower) < 0 {
+			inRange = false
+		}
+	} else if ri.upper != nil && bytes.Compare(key.Data(), ri.upper) >= 0 {
+		inRange = false
+	}
+	key.Free()
+
+	if !inRange {
+		return false
+	}
+	ri.count++
+	return true
+}
+
+// RawKey returns the current key's raw, still-ETF-encoded bytes. It is
+// used internally by callers (such as Dir and Count) that need to
+// recognize reserved-namespace keys without paying for a full decode.
+func (ri *RangeIterator) RawKey() []byte {
+	key := ri.iter.Key()
+	defer key.Free()
+	return append([]byte{}, key.Data()...)
+}
+
+// Key returns the current key, decoded as an ErlTerm.
+func (ri *RangeIterator) Key() etf.ErlTerm {
+	key := ri.iter.Key()
+	defer key.Free()
+	k, err := etf.DecodeErlTerm(key.Data())
+	if err != nil {
+		ri.err = err
+		return nil
+	}
+	return k
+}
+
+// Value returns the current value, decoded as an ErlTerm.
+func (ri *RangeIterator) Value() etf.ErlTerm {
+	value := ri.iter.Value()
+	defer value.Free()
+	v, err := etf.DecodeErlTerm(value.Data())
+	if err != nil {
+		ri.err = err
+		return nil
+	}
+	return v
+}
+
+// Err returns the first error encountered during iteration, if any.
+func (ri *RangeIterator) Err() error {
+	if ri.err != nil {
+		return ri.err
+	}
+	return ri.iter.Err()
+}
+
+// Release releases the resources held by the iterator. It must be called
+// exactly once, whether or not iteration ran to completion.
+func (ri *RangeIterator) Release() {
+	ri.iter.Close()
+	if ri.ownsRO {
+		ri.ro.Destroy()
+	}
+}
@@ -1,11 +1,24 @@
 package kvs
 
-import "github.com/iho/etf"
+import (
+	"sync"
 
-// RocksDB is a key-value store implementation using RocksDB as the backend.
+	"github.com/iho/etf"
+)
 
+// KVSRocksDB is a key-value store implementation using RocksDB as the
+// backend. It is single-table by design: db holds exactly one table's
+// keyspace (primary records plus that table's own index/ and seq/
+// namespaces), and the KVS interface it implements has no table
+// argument anywhere. A deployment with several tables opens one
+// RocksDB and one KVSRocksDB per table.
 type KVSRocksDB struct {
 	db *RocksDB
+
+	mu      sync.RWMutex
+	indexes []indexExtractor
+
+	seqMu sync.Mutex
 }
 
 func NewKVSRocksDB(db *RocksDB) *KVSRocksDB {
@@ -14,8 +27,106 @@ func NewKVSRocksDB(db *RocksDB) *KVSRocksDB {
 	}
 }
 
+// RegisterIndex declares a secondary index on field, extracted from each
+// record by extractor. Once registered, every Put and Delete keeps the
+// index up to date, and Index(field, value) can look up records by it.
+// RegisterIndex does not backfill existing records; call it before any
+// Put for the table, or reindex separately.
+func (r *KVSRocksDB) RegisterIndex(field etf.ErlTerm, extractor func(etf.ErlTerm) etf.ErlTerm) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexes = append(r.indexes, indexExtractor{field: field, extract: extractor})
+}
+
 func (r *KVSRocksDB) Put(id etf.ErlTerm, data etf.ErlTerm) error {
-	return r.db.SaveReader(id, data)
+	idb, err := etf.EncodeErlTerm(id, true)
+	if err != nil {
+		return err
+	}
+	datab, err := etf.EncodeErlTerm(data, true)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	indexes := r.indexes
+	r.mu.RUnlock()
+
+	// Index maintenance reads the old record and then writes a batch
+	// derived from it; without a lock, two concurrent Puts for the same
+	// id could both read the same old value and each emit their own
+	// index update, leaving an orphaned index entry. txnMu (shared with
+	// Txn and Append) serializes that read-modify-write.
+	r.db.txnMu.Lock()
+	defer r.db.txnMu.Unlock()
+
+	batch := r.db.NewBatch()
+	if err := batch.Put(idb, datab); err != nil {
+		return err
+	}
+
+	if len(indexes) > 0 {
+		oldb, err := r.db.Get(idb)
+		if err != nil {
+			return err
+		}
+		var old etf.ErlTerm
+		if oldb != nil {
+			old, err = etf.DecodeErlTerm(oldb)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, idx := range indexes {
+			if err := r.reindexField(batch, idx, id, old, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return batch.Write()
+}
+
+// reindexField updates a single index's entries for id when its record
+// changes from old (nil if id did not previously exist) to data.
+func (r *KVSRocksDB) reindexField(batch Batch, idx indexExtractor, id, old, data etf.ErlTerm) error {
+	newValue := idx.extract(data)
+
+	var oldValue etf.ErlTerm
+	if old != nil {
+		oldValue = idx.extract(old)
+	}
+
+	if oldValue != nil && newValue != nil {
+		same, err := indexValuesEqual(oldValue, newValue)
+		if err != nil {
+			return err
+		}
+		if same {
+			return nil
+		}
+	}
+
+	if oldValue != nil {
+		key, err := encodeIndexKey(idx.field, oldValue, id)
+		if err != nil {
+			return err
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	if newValue != nil {
+		key, err := encodeIndexKey(idx.field, newValue, id)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *KVSRocksDB) Get(id etf.ErlTerm) (etf.ErlTerm, error) {
@@ -23,17 +134,102 @@ func (r *KVSRocksDB) Get(id etf.ErlTerm) (etf.ErlTerm, error) {
 }
 
 func (r *KVSRocksDB) Delete(id etf.ErlTerm) error {
-	return r.db.Remove(id)
+	idb, err := etf.EncodeErlTerm(id, true)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	indexes := r.indexes
+	r.mu.RUnlock()
+
+	// See the matching comment in Put: this serializes the read-modify-write
+	// of index entries against concurrent Put/Delete calls for the same id.
+	r.db.txnMu.Lock()
+	defer r.db.txnMu.Unlock()
+
+	batch := r.db.NewBatch()
+	if err := batch.Delete(idb); err != nil {
+		return err
+	}
+
+	if len(indexes) > 0 {
+		oldb, err := r.db.Get(idb)
+		if err != nil {
+			return err
+		}
+		if oldb != nil {
+			old, err := etf.DecodeErlTerm(oldb)
+			if err != nil {
+				return err
+			}
+			for _, idx := range indexes {
+				value := idx.extract(old)
+				if value == nil {
+					continue
+				}
+				key, err := encodeIndexKey(idx.field, value, id)
+				if err != nil {
+					return err
+				}
+				if err := batch.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return batch.Write()
 }
 
-// I have no idea how to implement this function
+// Index returns the primary keys of every record whose indexed field
+// (registered via RegisterIndex) equals value.
 func (r *KVSRocksDB) Index(field etf.ErlTerm, value etf.ErlTerm) ([]etf.ErlTerm, error) {
-	return nil, nil
+	prefix, err := encodeIndexPrefix(field, value)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := r.db.NewIterator(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Release()
+
+	var ids []etf.ErlTerm
+	for iter.Next() {
+		idBytes := iter.Key()[len(prefix):]
+		id, err := etf.DecodeErlTerm(idBytes)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
-// I have no idea how to implement this function
+// Seq returns the next id in this table's monotonic sequence, as an
+// etf.Integer.
 func (r *KVSRocksDB) Seq() (etf.ErlTerm, error) {
-	return nil, nil
+	next, err := r.nextSeq(1)
+	if err != nil {
+		return nil, err
+	}
+	return etf.Integer(next), nil
+}
+
+// SeqN reserves a contiguous range of step ids for bulk inserts and
+// returns the first id in the range; the caller owns ids
+// [first, first+step).
+func (r *KVSRocksDB) SeqN(step int64) (etf.ErlTerm, error) {
+	first, err := r.nextSeq(step)
+	if err != nil {
+		return nil, err
+	}
+	return etf.Integer(first), nil
 }
 
 func (r *KVSRocksDB) Count() (int64, error) {
@@ -41,5 +237,5 @@ func (r *KVSRocksDB) Count() (int64, error) {
 }
 
 func (r *KVSRocksDB) Dir() ([]etf.ErlTerm, error) {
-	return r.db.All(etf.Atom(""))
+	return r.db.Dir()
 }
@@ -0,0 +1,153 @@
+package kvs
+
+import (
+	"bytes"
+
+	"github.com/linxGnu/grocksdb"
+)
+
+// Put stores value under key. It implements Backend.
+func (r *RocksDB) Put(key, value []byte) error {
+	return r.db.Put(r.wo, key, value)
+}
+
+// Get returns the value stored under key, or nil if key is not present.
+// It implements Backend.
+func (r *RocksDB) Get(key []byte) ([]byte, error) {
+	return r.db.GetBytes(r.ro, key)
+}
+
+// Delete removes key from the database. It implements Backend.
+func (r *RocksDB) Delete(key []byte) error {
+	return r.db.Delete(r.wo, key)
+}
+
+// NewIterator returns an Iterator over all keys with the given prefix,
+// starting at start (or at prefix itself if start is nil). It implements
+// Backend.
+func (r *RocksDB) NewIterator(prefix, start []byte) (Iterator, error) {
+	iter := r.db.NewIterator(r.ro)
+	return newRocksIterator(iter, prefix, start), nil
+}
+
+// NewBatch returns a Batch that buffers writes for atomic application via
+// Write. It implements Backend.
+func (r *RocksDB) NewBatch() Batch {
+	return &rocksBatch{db: r.db, wo: r.wo, batch: grocksdb.NewWriteBatch()}
+}
+
+// Snapshot returns a point-in-time, read-only view of the database. It
+// implements Backend.
+func (r *RocksDB) Snapshot() (Snapshot, error) {
+	snap := r.db.NewSnapshot()
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+	return &rocksSnapshot{db: r.db, ro: ro, snap: snap}, nil
+}
+
+// rocksIterator adapts a *grocksdb.Iterator, bounded to a key prefix, to
+// the Iterator interface.
+type rocksIterator struct {
+	iter     *grocksdb.Iterator
+	upper    []byte
+	started  bool
+	released bool
+}
+
+func newRocksIterator(iter *grocksdb.Iterator, prefix, start []byte) *rocksIterator {
+	seekKey := prefix
+	if start != nil {
+		seekKey = start
+	}
+	iter.Seek(seekKey)
+	return &rocksIterator{iter: iter, upper: prefixUpperBound(prefix)}
+}
+
+func (it *rocksIterator) Next() bool {
+	if it.started {
+		it.iter.Next()
+	}
+	it.started = true
+
+	if !it.iter.Valid() {
+		return false
+	}
+	if it.upper != nil {
+		key := it.iter.Key()
+		defer key.Free()
+		if bytes.Compare(key.Data(), it.upper) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (it *rocksIterator) Key() []byte {
+	key := it.iter.Key()
+	defer key.Free()
+	return append([]byte{}, key.Data()...)
+}
+
+func (it *rocksIterator) Value() []byte {
+	value := it.iter.Value()
+	defer value.Free()
+	return append([]byte{}, value.Data()...)
+}
+
+func (it *rocksIterator) Err() error {
+	return it.iter.Err()
+}
+
+func (it *rocksIterator) Release() {
+	if it.released {
+		return
+	}
+	it.released = true
+	it.iter.Close()
+}
+
+// rocksBatch adapts a *grocksdb.WriteBatch to the Batch interface.
+type rocksBatch struct {
+	db    *grocksdb.DB
+	wo    *grocksdb.WriteOptions
+	batch *grocksdb.WriteBatch
+}
+
+func (b *rocksBatch) Put(key, value []byte) error {
+	b.batch.Put(key, value)
+	return nil
+}
+
+func (b *rocksBatch) Delete(key []byte) error {
+	b.batch.Delete(key)
+	return nil
+}
+
+func (b *rocksBatch) Write() error {
+	return b.db.Write(b.wo, b.batch)
+}
+
+func (b *rocksBatch) Reset() {
+	b.batch.Clear()
+}
+
+// rocksSnapshot adapts a *grocksdb.Snapshot to the Snapshot interface.
+type rocksSnapshot struct {
+	db   *grocksdb.DB
+	ro   *grocksdb.ReadOptions
+	snap *grocksdb.Snapshot
+}
+
+func (s *rocksSnapshot) Get(key []byte) ([]byte, error) {
+	return s.db.GetBytes(s.ro, key)
+}
+
+func (s *rocksSnapshot) NewIterator(prefix, start []byte) (Iterator, error) {
+	iter := s.db.NewIterator(s.ro)
+	return newRocksIterator(iter, prefix, start), nil
+}
+
+func (s *rocksSnapshot) Release() {
+	s.db.ReleaseSnapshot(s.snap)
+	s.ro.Destroy()
+}
@@ -0,0 +1,25 @@
+package kvs_test
+
+import (
+	"testing"
+
+	"github.com/iho/kvs"
+	"github.com/iho/kvs/dbtest"
+	"github.com/linxGnu/grocksdb"
+)
+
+func TestRocksBackend(t *testing.T) {
+	dbtest.TestBackend(t, func(t *testing.T) kvs.Backend {
+		opts := grocksdb.NewDefaultOptions()
+		opts.SetCreateIfMissing(true)
+
+		dir := t.TempDir()
+		db, err := grocksdb.OpenDb(opts, dir)
+		if err != nil {
+			t.Fatalf("OpenDb: %v", err)
+		}
+		t.Cleanup(db.Close)
+
+		return kvs.NewRocksDB(db, grocksdb.NewDefaultReadOptions(), grocksdb.NewDefaultWriteOptions(), dir)
+	})
+}
@@ -0,0 +1,114 @@
+package kvs
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/iho/etf"
+)
+
+// seqCounterKey is the reserved key the sequence counter lives under.
+// KVSRocksDB has no table argument anywhere in its method set (see the
+// KVS interface in kvs.go) — one KVSRocksDB/RocksDB instance is one
+// table, so one counter key and one seqMu per instance is the whole
+// keyspace, not a shared one. A deployment with several tables gives
+// each its own RocksDB (and so its own KVSRocksDB), the same way it
+// gives each its own directory. seqCounterKey shares the "seq/"
+// namespace so the one-time full key scan can recognize and skip it when
+// seeding the counter from existing data.
+var (
+	seqKeyPrefix  = []byte("seq/")
+	seqCounterKey = append(append([]byte{}, seqKeyPrefix...), []byte("counter")...)
+	errSeqNotInt  = errors.New("kvs: sequence counter is not an integer")
+)
+
+// nextSeq reserves a contiguous range of step ids and returns the first
+// id in that range via a locked read-increment-write of seqCounterKey,
+// scoped to this KVSRocksDB's own table. If the counter has never been
+// written, it is seeded from max(existing primary keys)+1 with a
+// one-time full key scan, so a table populated before Seq was ever
+// called doesn't collide with it.
+func (r *KVSRocksDB) nextSeq(step int64) (int64, error) {
+	r.seqMu.Lock()
+	defer r.seqMu.Unlock()
+
+	current, err := r.loadSeq()
+	if err != nil {
+		return 0, err
+	}
+
+	next := current + 1
+	newCounter := current + step
+
+	counterb, err := etf.EncodeErlTerm(etf.Integer(newCounter), true)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.db.Put(seqCounterKey, counterb); err != nil {
+		return 0, err
+	}
+
+	return next, nil
+}
+
+// loadSeq returns the current value of the sequence counter, seeding it
+// from existing keys if it has never been written.
+func (r *KVSRocksDB) loadSeq() (int64, error) {
+	counterb, err := r.db.Get(seqCounterKey)
+	if err != nil {
+		return 0, err
+	}
+	if counterb == nil {
+		return r.maxPrimaryKeyInt()
+	}
+
+	term, err := etf.DecodeErlTerm(counterb)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := term.(etf.Integer)
+	if !ok {
+		return 0, errSeqNotInt
+	}
+	return int64(n), nil
+}
+
+// maxPrimaryKeyInt scans every primary record key (i.e. excluding the
+// index/ and seq/ reserved namespaces) and returns the largest one that
+// decodes as an etf.Integer, or 0 if there is none. It is only run once,
+// the first time Seq or SeqN is called against a table that has never
+// had a counter written.
+func (r *KVSRocksDB) maxPrimaryKeyInt() (int64, error) {
+	iter, err := r.db.NewIterator(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Release()
+
+	var max int64
+	for iter.Next() {
+		key := iter.Key()
+		if isReservedKey(key) {
+			continue
+		}
+
+		term, err := etf.DecodeErlTerm(key)
+		if err != nil {
+			continue
+		}
+		if n, ok := term.(etf.Integer); ok && int64(n) > max {
+			max = int64(n)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+	return max, nil
+}
+
+// isReservedKey reports whether key belongs to one of KVSRocksDB's
+// internal namespaces (secondary indexes, the sequence counter) rather
+// than to an application record.
+func isReservedKey(key []byte) bool {
+	return bytes.HasPrefix(key, indexKeyPrefix) || bytes.HasPrefix(key, seqKeyPrefix)
+}
@@ -3,6 +3,7 @@ package kvs
 import (
 	"bytes"
 	"errors"
+	"sync"
 
 	"github.com/iho/etf"
 	"github.com/linxGnu/grocksdb"
@@ -17,89 +18,64 @@ type RocksDB struct {
 	db *grocksdb.DB
 	ro *grocksdb.ReadOptions
 	wo *grocksdb.WriteOptions
+
+	// path is the on-disk directory db was opened from. It is only used
+	// to tell a BackupEngine where to restore to; see Backup/Restore in
+	// kvs_backup.go.
+	path string
+
+	// txnMu serializes the check-then-write section of every write Txn
+	// against this database, so e.g. two concurrent Append calls for the
+	// same record can't both observe "not found" before either commits.
+	// See Txn and NewTxn in kvs_txn.go.
+	txnMu sync.Mutex
 }
 
-func NewRocksDB(db *grocksdb.DB, ro *grocksdb.ReadOptions, wo *grocksdb.WriteOptions) *RocksDB {
+func NewRocksDB(db *grocksdb.DB, ro *grocksdb.ReadOptions, wo *grocksdb.WriteOptions, path string) *RocksDB {
 	return &RocksDB{
-		db: db,
-		ro: ro,
-		wo: wo,
+		db:   db,
+		ro:   ro,
+		wo:   wo,
+		path: path,
 	}
 }
 
 // Cut removes all the data associated with a specific feed or key range.
 func (r *RocksDB) Cut(feed etf.ErlTerm) error {
-	start, err := etf.EncodeErlTerm(feed, true)
+	iter, err := r.Range(RangeOptions{Prefix: feed})
 	if err != nil {
 		return err
 	}
+	defer iter.Release()
 
-	// To define an end key, we can append a byte that is greater than any possible byte in the start key
-	end := append(start, 0xFF)
-
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
-
-	batch := grocksdb.NewWriteBatch()
-	defer batch.Destroy()
-
-	for iter.Seek(start); iter.Valid(); iter.Next() {
-		key := iter.Key()
-		if bytes.Compare(key.Data(), end) >= 0 {
-			key.Free()
-			break
+	batch := r.NewBatch()
+	for iter.Next() {
+		if err := batch.Delete(iter.RawKey()); err != nil {
+			return err
 		}
-		batch.Delete(key.Data())
-		key.Free()
 	}
-
 	if err := iter.Err(); err != nil {
 		return err
 	}
 
-	return r.db.Write(r.wo, batch)
+	return batch.Write()
 }
 
 // Take retrieves a specific number of key-value pairs starting from a given key.
 func (r *RocksDB) Take(startKey etf.ErlTerm, num int) (etf.Map, error) {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
-
-	result := etf.Map{}
-	start, err := etf.EncodeErlTerm(startKey, true)
+	if num <= 0 {
+		return etf.Map{}, nil
+	}
+	iter, err := r.Range(RangeOptions{Start: startKey, Limit: num})
 	if err != nil {
 		return nil, err
 	}
+	defer iter.Release()
 
-	iter.Seek(start)
-	count := 0
-
-	for ; iter.Valid() && count < num; iter.Next() {
-		key := iter.Key()
-		value := iter.Value()
-
-		k, err := etf.DecodeErlTerm(key.Data())
-		if err != nil {
-			key.Free()
-			value.Free()
-			return nil, err
-		}
-
-		v, err := etf.DecodeErlTerm(value.Data())
-		if err != nil {
-			key.Free()
-			value.Free()
-			return nil, err
-		}
-
-		result = append(result, etf.MapElem{Key: k, Value: v})
-
-		key.Free()
-		value.Free()
-
-		count++
+	result := etf.Map{}
+	for iter.Next() {
+		result = append(result, etf.MapElem{Key: iter.Key(), Value: iter.Value()})
 	}
-
 	if err := iter.Err(); err != nil {
 		return nil, err
 	}
@@ -109,58 +85,96 @@ func (r *RocksDB) Take(startKey etf.ErlTerm, num int) (etf.Map, error) {
 
 // Drop removes a specific number of key-value pairs starting from a given key.
 func (r *RocksDB) Drop(startKey etf.ErlTerm, num int) error {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
-
-	start, err := etf.EncodeErlTerm(startKey, true)
+	if num <= 0 {
+		return nil
+	}
+	iter, err := r.Range(RangeOptions{Start: startKey, Limit: num})
 	if err != nil {
 		return err
 	}
+	defer iter.Release()
 
-	batch := grocksdb.NewWriteBatch()
-	defer batch.Destroy()
+	batch := r.NewBatch()
+	for iter.Next() {
+		if err := batch.Delete(iter.RawKey()); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
 
-	iter.Seek(start)
-	count := 0
+	return batch.Write()
+}
 
-	for ; iter.Valid() && count < num; iter.Next() {
-		key := iter.Key()
-		batch.Delete(key.Data())
-		key.Free()
-		count++
+// Dir lists every primary record key currently stored, excluding the
+// reserved index/ and seq/ namespaces.
+func (r *RocksDB) Dir() ([]etf.ErlTerm, error) {
+	iter, err := r.Range(RangeOptions{})
+	if err != nil {
+		return nil, err
 	}
+	defer iter.Release()
 
+	var keys []etf.ErlTerm
+	for iter.Next() {
+		raw := iter.RawKey()
+		if isReservedKey(raw) {
+			continue
+		}
+		k, err := etf.DecodeErlTerm(raw)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
 	if err := iter.Err(); err != nil {
-		return err
+		return nil, err
 	}
-
-	return r.db.Write(r.wo, batch)
+	return keys, nil
 }
 
-// Top retrieves the first key-value pair in the database
-func (r *RocksDB) Top() (etf.ErlTerm, etf.ErlTerm, error) {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
+// Count returns the number of primary records currently stored,
+// excluding the reserved index/ and seq/ namespaces.
+func (r *RocksDB) Count() (int64, error) {
+	iter, err := r.Range(RangeOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Release()
 
-	iter.SeekToFirst()
-	if iter.Valid() {
-		key := iter.Key()
-		value := iter.Value()
+	var count int64
+	for iter.Next() {
+		if isReservedKey(iter.RawKey()) {
+			continue
+		}
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
 
-		defer key.Free()
-		defer value.Free()
+// Top retrieves the first primary record in the database, excluding the
+// reserved index/ and seq/ namespaces.
+func (r *RocksDB) Top() (etf.ErlTerm, etf.ErlTerm, error) {
+	iter, err := r.Range(RangeOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Release()
 
-		k, err := etf.DecodeErlTerm(key.Data())
-		if err != nil {
-			return nil, nil, err
+	for iter.Next() {
+		if isReservedKey(iter.RawKey()) {
+			continue
 		}
-		v, err := etf.DecodeErlTerm(value.Data())
-		if err != nil {
+		k, v := iter.Key(), iter.Value()
+		if err := iter.Err(); err != nil {
 			return nil, nil, err
 		}
 		return k, v, nil
 	}
-
 	if err := iter.Err(); err != nil {
 		return nil, nil, err
 	}
@@ -168,30 +182,25 @@ func (r *RocksDB) Top() (etf.ErlTerm, etf.ErlTerm, error) {
 	return nil, nil, errors.New("database is empty")
 }
 
-// Bot retrieves the last key-value pair in the database
+// Bot retrieves the last primary record in the database, excluding the
+// reserved index/ and seq/ namespaces.
 func (r *RocksDB) Bot() (etf.ErlTerm, etf.ErlTerm, error) {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
-
-	iter.SeekToLast()
-	if iter.Valid() {
-		key := iter.Key()
-		value := iter.Value()
-
-		defer key.Free()
-		defer value.Free()
+	iter, err := r.Range(RangeOptions{Reverse: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Release()
 
-		k, err := etf.DecodeErlTerm(key.Data())
-		if err != nil {
-			return nil, nil, err
+	for iter.Next() {
+		if isReservedKey(iter.RawKey()) {
+			continue
 		}
-		v, err := etf.DecodeErlTerm(value.Data())
-		if err != nil {
+		k, v := iter.Key(), iter.Value()
+		if err := iter.Err(); err != nil {
 			return nil, nil, err
 		}
 		return k, v, nil
 	}
-
 	if err := iter.Err(); err != nil {
 		return nil, nil, err
 	}
@@ -199,47 +208,35 @@ func (r *RocksDB) Bot() (etf.ErlTerm, etf.ErlTerm, error) {
 	return nil, nil, errors.New("database is empty")
 }
 
-// Next retrieves the next key-value pair after the provided startKey
+// Next retrieves the next primary record after the provided startKey,
+// excluding the reserved index/ and seq/ namespaces.
 func (r *RocksDB) Next(startKey etf.ErlTerm) (etf.ErlTerm, etf.ErlTerm, error) {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
+	iter, err := r.Range(RangeOptions{Start: startKey})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Release()
 
 	start, err := etf.EncodeErlTerm(startKey, true)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	iter.Seek(start)
-	if iter.Valid() {
-		// If the startKey exists, move to the next key
-		key := iter.Key()
-		if bytes.Equal(key.Data(), start) {
-			iter.Next()
-			key.Free()
-		} else {
-			key.Free()
+	for iter.Next() {
+		raw := iter.RawKey()
+		// Skip startKey itself, if present, to land on the next key.
+		if bytes.Equal(raw, start) {
+			continue
 		}
-	}
-
-	if iter.Valid() {
-		key := iter.Key()
-		value := iter.Value()
-
-		defer key.Free()
-		defer value.Free()
-
-		k, err := etf.DecodeErlTerm(key.Data())
-		if err != nil {
-			return nil, nil, err
+		if isReservedKey(raw) {
+			continue
 		}
-		v, err := etf.DecodeErlTerm(value.Data())
-		if err != nil {
+		k, v := iter.Key(), iter.Value()
+		if err := iter.Err(); err != nil {
 			return nil, nil, err
 		}
-
 		return k, v, nil
 	}
-
 	if err := iter.Err(); err != nil {
 		return nil, nil, err
 	}
@@ -247,43 +244,25 @@ func (r *RocksDB) Next(startKey etf.ErlTerm) (etf.ErlTerm, etf.ErlTerm, error) {
 	return nil, nil, errors.New("no next key")
 }
 
-// Prev retrieves the previous key-value pair before the provided startKey
+// Prev retrieves the previous primary record before the provided
+// startKey, excluding the reserved index/ and seq/ namespaces.
 func (r *RocksDB) Prev(startKey etf.ErlTerm) (etf.ErlTerm, etf.ErlTerm, error) {
-	iter := r.db.NewIterator(r.ro)
-	defer iter.Close()
-
-	start, err := etf.EncodeErlTerm(startKey, true)
+	iter, err := r.Range(RangeOptions{End: startKey, Reverse: true})
 	if err != nil {
 		return nil, nil, err
 	}
+	defer iter.Release()
 
-	iter.Seek(start)
-	if iter.Valid() {
-		iter.Prev()
-	} else {
-		// If Seek failed, position at the last key less than startKey
-		iter.SeekForPrev(start)
-	}
-
-	if iter.Valid() {
-		key := iter.Key()
-		value := iter.Value()
-
-		defer key.Free()
-		defer value.Free()
-
-		k, err := etf.DecodeErlTerm(key.Data())
-		if err != nil {
-			return nil, nil, err
+	for iter.Next() {
+		if isReservedKey(iter.RawKey()) {
+			continue
 		}
-		v, err := etf.DecodeErlTerm(value.Data())
-		if err != nil {
+		k, v := iter.Key(), iter.Value()
+		if err := iter.Err(); err != nil {
 			return nil, nil, err
 		}
-
 		return k, v, nil
 	}
-
 	if err := iter.Err(); err != nil {
 		return nil, nil, err
 	}
@@ -336,36 +315,29 @@ func (r *RocksDB) Remove(key etf.ErlTerm) error {
 	return r.db.Delete(r.wo, keyb)
 }
 
-// Append adds a record to the database if it doesn't already exist, otherwise returns the existing key
+// Append adds a record to the database if it doesn't already exist, otherwise returns the existing key.
+// The existence check and the write happen inside a single Txn, so two
+// concurrent Append calls for the same record can't race each other into
+// both believing the record is new (see Txn.Append).
 func (r *RocksDB) Append(rec etf.ErlTerm, feed etf.ErlTerm) (etf.ErlTerm, error) {
-	recb, err := etf.EncodeErlTerm(rec, true)
-	if err != nil {
-		return nil, err
-	}
-	existingValue, err := r.db.GetBytes(r.ro, recb)
-	if err != nil {
-		return nil, err
-	}
-
-	if existingValue != nil {
-		// Record already exists, return existing record
-		return rec, nil
-	}
+	txn := r.NewTxn(false)
+	defer txn.Rollback()
 
-	feedb, err := etf.EncodeErlTerm(feed, true)
+	id, err := txn.Append(rec, feed)
 	if err != nil {
 		return nil, err
 	}
-
-	// Record does not exist, insert it
-	if err := r.db.Put(r.wo, recb, feedb); err != nil {
+	if err := txn.Commit(); err != nil {
 		return nil, err
 	}
-
-	return rec, nil
+	return id, nil
 }
 
-// Close closes the database
-func (r *RocksDB) Close() {
+// Close closes the database. It implements Backend.
+func (r *RocksDB) Close() error {
 	r.db.Close()
+	return nil
 }
+
+// var _ Backend asserts that RocksDB satisfies the Backend interface.
+var _ Backend = (*RocksDB)(nil)
@@ -0,0 +1,233 @@
+package kvs
+
+import (
+	"errors"
+
+	"github.com/iho/etf"
+	"github.com/linxGnu/grocksdb"
+)
+
+// errTxnFinished is returned by Commit once Commit or Rollback has
+// already been called. The other Txn methods do not check for this.
+var errTxnFinished = errors.New("kvs: transaction already committed or rolled back")
+
+// Txn is a snapshot-isolated batch of reads and writes against a
+// RocksDB. Reads see a consistent point-in-time view of the database
+// (taken when the Txn was created) overlaid with the Txn's own
+// not-yet-committed writes, giving read-your-writes semantics without
+// those writes being visible to anyone else until Commit.
+//
+// A write Txn (readOnly == false) also holds its RocksDB's txnMu for its
+// whole lifetime, so the existence-check-then-write pattern used by
+// Append is atomic with respect to other Txns on the same database.
+type Txn struct {
+	r        *RocksDB
+	readOnly bool
+
+	snap *grocksdb.Snapshot
+	ro   *grocksdb.ReadOptions
+	wb   *grocksdb.WriteBatchWithIndex
+
+	done bool
+}
+
+// NewTxn starts a new transaction against r. Write transactions must be
+// finished with Commit or Rollback; read-only transactions only need
+// Rollback to release their snapshot.
+func (r *RocksDB) NewTxn(readOnly bool) *Txn {
+	if !readOnly {
+		r.txnMu.Lock()
+	}
+
+	snap := r.db.NewSnapshot()
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+
+	return &Txn{
+		r:        r,
+		readOnly: readOnly,
+		snap:     snap,
+		ro:       ro,
+		wb:       grocksdb.NewWriteBatchWithIndex(),
+	}
+}
+
+// Get returns the value stored under key, seeing any not-yet-committed
+// write this Txn has made to it.
+func (t *Txn) Get(key etf.ErlTerm) (etf.ErlTerm, error) {
+	keyb, err := etf.EncodeErlTerm(key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.wb.GetFromBatchAndDB(t.r.db, t.ro, keyb)
+	if err != nil {
+		return nil, err
+	}
+	defer value.Free()
+
+	if !value.Exists() {
+		return nil, errors.New("key not found")
+	}
+	return etf.DecodeErlTerm(value.Data())
+}
+
+// Put buffers a write of key/data, visible to this Txn's own Get/Take
+// calls but not committed to the database until Commit.
+func (t *Txn) Put(key, data etf.ErlTerm) error {
+	keyb, err := etf.EncodeErlTerm(key, true)
+	if err != nil {
+		return err
+	}
+	datab, err := etf.EncodeErlTerm(data, true)
+	if err != nil {
+		return err
+	}
+	t.wb.Put(keyb, datab)
+	return nil
+}
+
+// Delete buffers a deletion of key.
+func (t *Txn) Delete(key etf.ErlTerm) error {
+	keyb, err := etf.EncodeErlTerm(key, true)
+	if err != nil {
+		return err
+	}
+	t.wb.Delete(keyb)
+	return nil
+}
+
+// Append buffers a write of rec to feed if it doesn't already exist
+// (checking both the database snapshot and this Txn's own buffered
+// writes), otherwise it leaves the database untouched and returns the
+// existing record. Combined with NewTxn holding txnMu for write Txns,
+// this makes the existence check and the write atomic.
+func (t *Txn) Append(rec, feed etf.ErlTerm) (etf.ErlTerm, error) {
+	recb, err := etf.EncodeErlTerm(rec, true)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := t.wb.GetFromBatchAndDB(t.r.db, t.ro, recb)
+	if err != nil {
+		return nil, err
+	}
+	exists := existing.Exists()
+	existing.Free()
+
+	if exists {
+		return rec, nil
+	}
+
+	feedb, err := etf.EncodeErlTerm(feed, true)
+	if err != nil {
+		return nil, err
+	}
+	t.wb.Put(recb, feedb)
+	return rec, nil
+}
+
+// Take retrieves up to num key-value pairs starting from startKey,
+// including this Txn's own buffered writes.
+func (t *Txn) Take(startKey etf.ErlTerm, num int) (etf.Map, error) {
+	start, err := etf.EncodeErlTerm(startKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	base := t.r.db.NewIterator(t.ro)
+	iter := t.wb.NewIteratorWithBase(base)
+	defer iter.Close()
+
+	result := etf.Map{}
+	count := 0
+	for iter.Seek(start); iter.Valid() && count < num; iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+
+		k, err := etf.DecodeErlTerm(key.Data())
+		if err != nil {
+			key.Free()
+			value.Free()
+			return nil, err
+		}
+		v, err := etf.DecodeErlTerm(value.Data())
+		if err != nil {
+			key.Free()
+			value.Free()
+			return nil, err
+		}
+		result = append(result, etf.MapElem{Key: k, Value: v})
+
+		key.Free()
+		value.Free()
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Cut buffers the deletion of every key with the given prefix, including
+// keys this Txn itself has already buffered a write for.
+func (t *Txn) Cut(feed etf.ErlTerm) error {
+	prefix, err := etf.EncodeErlTerm(feed, true)
+	if err != nil {
+		return err
+	}
+	upper := prefixUpperBound(prefix)
+
+	base := t.r.db.NewIterator(t.ro)
+	iter := t.wb.NewIteratorWithBase(base)
+	defer iter.Close()
+
+	for iter.Seek(prefix); iter.Valid(); iter.Next() {
+		key := iter.Key()
+		if upper != nil && string(key.Data()) >= string(upper) {
+			key.Free()
+			break
+		}
+		t.wb.Delete(append([]byte{}, key.Data()...))
+		key.Free()
+	}
+	return iter.Err()
+}
+
+// Commit atomically applies every buffered write to the database and
+// releases the Txn's snapshot and (for write Txns) txnMu. Commit must
+// not be called more than once; Rollback after a successful Commit is a
+// harmless no-op, which lets callers defer Rollback unconditionally.
+func (t *Txn) Commit() error {
+	if t.done {
+		return errTxnFinished
+	}
+	if t.readOnly {
+		t.finish()
+		return errors.New("kvs: cannot Commit a read-only transaction")
+	}
+
+	err := t.r.db.Write(t.r.wo, t.wb.WriteBatch())
+	t.finish()
+	return err
+}
+
+// Rollback discards every buffered write and releases the Txn's
+// snapshot and (for write Txns) txnMu. It is a no-op if the Txn has
+// already been committed or rolled back.
+func (t *Txn) Rollback() {
+	if t.done {
+		return
+	}
+	t.finish()
+}
+
+func (t *Txn) finish() {
+	t.done = true
+	t.wb.Destroy()
+	t.r.db.ReleaseSnapshot(t.snap)
+	t.ro.Destroy()
+	if !t.readOnly {
+		t.r.txnMu.Unlock()
+	}
+}
@@ -0,0 +1,52 @@
+package kvs_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/iho/etf"
+	"github.com/iho/kvs"
+	"github.com/linxGnu/grocksdb"
+)
+
+func TestConcurrentAppendWritesOnce(t *testing.T) {
+	opts := grocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+
+	dir := t.TempDir()
+	db, err := grocksdb.OpenDb(opts, dir)
+	if err != nil {
+		t.Fatalf("OpenDb: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	rdb := kvs.NewRocksDB(db, grocksdb.NewDefaultReadOptions(), grocksdb.NewDefaultWriteOptions(), dir)
+
+	const n = 16
+	rec := etf.Atom("same_record")
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = rdb.Append(rec, etf.Integer(i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Append #%d: %v", i, err)
+		}
+	}
+
+	count, err := rdb.Count()
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("Count after %d concurrent Append calls: got %d, want 1", n, count)
+	}
+}
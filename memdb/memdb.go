@@ -0,0 +1,191 @@
+// Package memdb provides an in-memory kvs.Backend, useful for tests and
+// tools that need a KVS instance without linking CGO-dependent storage
+// engines such as RocksDB.
+package memdb
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/iho/kvs"
+)
+
+// ErrKeyNotFound reports a missing key. MemBackend's Get does not return
+// it: an absent key yields a nil value and a nil error.
+var ErrKeyNotFound = errors.New("memdb: key not found")
+
+// MemBackend is a kvs.Backend backed by a map guarded by an RWMutex;
+// iterators sort the matching keys when they are created. It keeps every key/value pair in memory and is not durable
+// across restarts.
+type MemBackend struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// New returns an empty MemBackend.
+func New() *MemBackend {
+	return &MemBackend{data: make(map[string][]byte)}
+}
+
+// Put implements kvs.Backend.
+func (m *MemBackend) Put(key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// Get implements kvs.Backend.
+func (m *MemBackend) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte{}, v...), nil
+}
+
+// Delete implements kvs.Backend.
+func (m *MemBackend) Delete(key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, string(key))
+	return nil
+}
+
+// NewIterator implements kvs.Backend.
+func (m *MemBackend) NewIterator(prefix, start []byte) (kvs.Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.data))
+	for k := range m.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	from := 0
+	if start != nil {
+		from = sort.SearchStrings(keys, string(start))
+	}
+	keys = keys[from:]
+
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = append([]byte{}, m.data[k]...)
+	}
+
+	return &memIterator{keys: keys, values: values, pos: -1}, nil
+}
+
+// NewBatch implements kvs.Backend.
+func (m *MemBackend) NewBatch() kvs.Batch {
+	return &memBatch{db: m}
+}
+
+// Snapshot implements kvs.Backend.
+func (m *MemBackend) Snapshot() (kvs.Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data := make(map[string][]byte, len(m.data))
+	for k, v := range m.data {
+		data[k] = append([]byte{}, v...)
+	}
+	return &memSnapshot{data: data}, nil
+}
+
+// Close implements kvs.Backend.
+func (m *MemBackend) Close() error {
+	return nil
+}
+
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	pos    int
+}
+
+func (it *memIterator) Next() bool {
+	it.pos++
+	return it.pos < len(it.keys)
+}
+
+func (it *memIterator) Key() []byte   { return []byte(it.keys[it.pos]) }
+func (it *memIterator) Value() []byte { return it.values[it.pos] }
+func (it *memIterator) Err() error    { return nil }
+func (it *memIterator) Release()      {}
+
+type memBatch struct {
+	db  *MemBackend
+	ops []func(*MemBackend)
+}
+
+func (b *memBatch) Put(key, value []byte) error {
+	key, value = append([]byte{}, key...), append([]byte{}, value...)
+	b.ops = append(b.ops, func(m *MemBackend) { m.data[string(key)] = value })
+	return nil
+}
+
+func (b *memBatch) Delete(key []byte) error {
+	key = append([]byte{}, key...)
+	b.ops = append(b.ops, func(m *MemBackend) { delete(m.data, string(key)) })
+	return nil
+}
+
+func (b *memBatch) Write() error {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	for _, op := range b.ops {
+		op(b.db)
+	}
+	return nil
+}
+
+func (b *memBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// memSnapshot is a deep copy of the backend's data taken at Snapshot time.
+type memSnapshot struct {
+	data map[string][]byte
+}
+
+func (s *memSnapshot) Get(key []byte) ([]byte, error) {
+	v, ok := s.data[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (s *memSnapshot) NewIterator(prefix, start []byte) (kvs.Iterator, error) {
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	from := 0
+	if start != nil {
+		from = sort.SearchStrings(keys, string(start))
+	}
+	keys = keys[from:]
+
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = s.data[k]
+	}
+	return &memIterator{keys: keys, values: values, pos: -1}, nil
+}
+
+func (s *memSnapshot) Release() {}
+
+var _ kvs.Backend = (*MemBackend)(nil)
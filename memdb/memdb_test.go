@@ -0,0 +1,15 @@
+package memdb_test
+
+import (
+	"testing"
+
+	"github.com/iho/kvs"
+	"github.com/iho/kvs/dbtest"
+	"github.com/iho/kvs/memdb"
+)
+
+func TestMemBackend(t *testing.T) {
+	dbtest.TestBackend(t, func(t *testing.T) kvs.Backend {
+		return memdb.New()
+	})
+}
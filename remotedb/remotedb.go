@@ -0,0 +1,127 @@
+// Package remotedb provides a kvs.Backend that forwards every operation
+// to a backend served by another process over the network, so that a
+// single storage engine (typically RocksDB) can be shared by several KVS
+// instances without each of them linking CGO.
+//
+// It is built on net/rpc rather than gRPC: the wire contract here is a
+// handful of Put/Get/Delete/Range/Batch calls entirely internal to this
+// repo, so net/rpc's gob codec covers it without a .proto file or a
+// protoc-generated client/server pair. Reach for gRPC instead if this
+// backend ever needs to be called from outside Go, needs streaming
+// responses for large ranges, or needs TLS/auth middleware that net/rpc
+// doesn't provide.
+package remotedb
+
+import (
+	"errors"
+	"net/rpc"
+
+	"github.com/iho/kvs"
+)
+
+// ErrSnapshotUnsupported is returned by Snapshot, since a point-in-time
+// view of a remote backend cannot be represented cheaply over RPC.
+var ErrSnapshotUnsupported = errors.New("remotedb: snapshots are not supported")
+
+// RemoteBackend is a kvs.Backend that talks to a Server over net/rpc.
+// It is registered as its own Backend implementation rather than a
+// RocksDB/BoltDB-specific client so that any Backend can be exposed
+// remotely by wrapping it in a Server.
+type RemoteBackend struct {
+	client *rpc.Client
+}
+
+// Dial connects to a Server listening at addr.
+func Dial(addr string) (*RemoteBackend, error) {
+	client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteBackend{client: client}, nil
+}
+
+// Put implements kvs.Backend.
+func (r *RemoteBackend) Put(key, value []byte) error {
+	return r.client.Call("Server.Put", &PutArgs{Key: key, Value: value}, &Empty{})
+}
+
+// Get implements kvs.Backend.
+func (r *RemoteBackend) Get(key []byte) ([]byte, error) {
+	var reply GetReply
+	if err := r.client.Call("Server.Get", &GetArgs{Key: key}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+// Delete implements kvs.Backend.
+func (r *RemoteBackend) Delete(key []byte) error {
+	return r.client.Call("Server.Delete", &DeleteArgs{Key: key}, &Empty{})
+}
+
+// NewIterator implements kvs.Backend. The whole matching range is
+// fetched eagerly in one round trip, since RPC has no good way to stream
+// a live grocksdb-style cursor back to the caller.
+func (r *RemoteBackend) NewIterator(prefix, start []byte) (kvs.Iterator, error) {
+	var reply RangeReply
+	args := &RangeArgs{Prefix: prefix, Start: start}
+	if err := r.client.Call("Server.Range", args, &reply); err != nil {
+		return nil, err
+	}
+	return &remoteIterator{pairs: reply.Pairs, pos: -1}, nil
+}
+
+// NewBatch implements kvs.Backend.
+func (r *RemoteBackend) NewBatch() kvs.Batch {
+	return &remoteBatch{client: r.client}
+}
+
+// Snapshot implements kvs.Backend. Remote snapshots are not supported.
+func (r *RemoteBackend) Snapshot() (kvs.Snapshot, error) {
+	return nil, ErrSnapshotUnsupported
+}
+
+// Close implements kvs.Backend.
+func (r *RemoteBackend) Close() error {
+	return r.client.Close()
+}
+
+type remoteIterator struct {
+	pairs []KV
+	pos   int
+}
+
+func (it *remoteIterator) Next() bool {
+	it.pos++
+	return it.pos < len(it.pairs)
+}
+
+func (it *remoteIterator) Key() []byte   { return it.pairs[it.pos].Key }
+func (it *remoteIterator) Value() []byte { return it.pairs[it.pos].Value }
+func (it *remoteIterator) Err() error    { return nil }
+func (it *remoteIterator) Release()      {}
+
+type remoteBatch struct {
+	client *rpc.Client
+	ops    []BatchOp
+}
+
+func (b *remoteBatch) Put(key, value []byte) error {
+	b.ops = append(b.ops, BatchOp{Put: true, Key: key, Value: value})
+	return nil
+}
+
+func (b *remoteBatch) Delete(key []byte) error {
+	b.ops = append(b.ops, BatchOp{Put: false, Key: key})
+	return nil
+}
+
+func (b *remoteBatch) Write() error {
+	return b.client.Call("Server.Batch", &BatchArgs{Ops: b.ops}, &Empty{})
+}
+
+func (b *remoteBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+var _ kvs.Backend = (*RemoteBackend)(nil)
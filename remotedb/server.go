@@ -0,0 +1,125 @@
+package remotedb
+
+import (
+	"net"
+	"net/rpc"
+
+	"github.com/iho/kvs"
+)
+
+// Empty is the reply type for RPCs that return nothing but an error.
+type Empty struct{}
+
+// KV is a single key/value pair, as returned by Server.Range.
+type KV struct {
+	Key, Value []byte
+}
+
+// PutArgs are the arguments to Server.Put.
+type PutArgs struct{ Key, Value []byte }
+
+// GetArgs are the arguments to Server.Get.
+type GetArgs struct{ Key []byte }
+
+// GetReply is the reply from Server.Get.
+type GetReply struct{ Value []byte }
+
+// DeleteArgs are the arguments to Server.Delete.
+type DeleteArgs struct{ Key []byte }
+
+// RangeArgs are the arguments to Server.Range.
+type RangeArgs struct{ Prefix, Start []byte }
+
+// RangeReply is the reply from Server.Range.
+type RangeReply struct{ Pairs []KV }
+
+// BatchOp is a single buffered operation forwarded to Server.Batch. Put
+// is true for a put (Value is then meaningful) and false for a delete.
+type BatchOp struct {
+	Put        bool
+	Key, Value []byte
+}
+
+// BatchArgs are the arguments to Server.Batch.
+type BatchArgs struct{ Ops []BatchOp }
+
+// Server exposes a local kvs.Backend over net/rpc so that RemoteBackend
+// clients elsewhere on the network can share it.
+type Server struct {
+	backend kvs.Backend
+}
+
+// NewServer wraps backend for remote access.
+func NewServer(backend kvs.Backend) *Server {
+	return &Server{backend: backend}
+}
+
+// ListenAndServe registers Server and serves RPC requests on addr until
+// the listener fails or the process exits.
+func (s *Server) ListenAndServe(addr string) error {
+	if err := rpc.Register(s); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go rpc.ServeConn(conn)
+	}
+}
+
+// Put implements the Server.Put RPC.
+func (s *Server) Put(args *PutArgs, _ *Empty) error {
+	return s.backend.Put(args.Key, args.Value)
+}
+
+// Get implements the Server.Get RPC.
+func (s *Server) Get(args *GetArgs, reply *GetReply) error {
+	v, err := s.backend.Get(args.Key)
+	if err != nil {
+		return err
+	}
+	reply.Value = v
+	return nil
+}
+
+// Delete implements the Server.Delete RPC.
+func (s *Server) Delete(args *DeleteArgs, _ *Empty) error {
+	return s.backend.Delete(args.Key)
+}
+
+// Range implements the Server.Range RPC, eagerly collecting every
+// key/value pair in [start, prefix-upper-bound) into the reply.
+func (s *Server) Range(args *RangeArgs, reply *RangeReply) error {
+	iter, err := s.backend.NewIterator(args.Prefix, args.Start)
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	for iter.Next() {
+		reply.Pairs = append(reply.Pairs, KV{Key: iter.Key(), Value: iter.Value()})
+	}
+	return iter.Err()
+}
+
+// Batch implements the Server.Batch RPC, applying every op atomically
+// via the backend's own Batch.
+func (s *Server) Batch(args *BatchArgs, _ *Empty) error {
+	batch := s.backend.NewBatch()
+	for _, op := range args.Ops {
+		if op.Put {
+			if err := batch.Put(op.Key, op.Value); err != nil {
+				return err
+			}
+		} else if err := batch.Delete(op.Key); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}